@@ -0,0 +1,50 @@
+package vlog
+
+import "testing"
+
+func TestTextEncoderQuotesValuesThatWouldBreakLineStructure(t *testing.T) {
+	cases := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"plain", "ok", `level=INFO msg=hello body=ok`},
+		{"space", "two words", `level=INFO msg=hello body="two words"`},
+		{"newline", "line1\nline2", `level=INFO msg=hello body="line1\nline2"`},
+		{"carriage return", "a\rb", `level=INFO msg=hello body="a\rb"`},
+		{"equals", "k=v", `level=INFO msg=hello body="k=v"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := string(TextEncoder{}.Encode("INFO", "hello", []Field{KV("body", tc.value)}))
+			if got != tc.want {
+				t.Errorf("Encode() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTextEncoderNeverEmitsMoreThanOneLine(t *testing.T) {
+	got := string(TextEncoder{}.Encode("INFO", "hello", []Field{KV("body", "line1\nline2")}))
+	if n := countRunes(got, '\n'); n != 0 {
+		t.Fatalf("Encode() produced %d embedded newlines, want 0 (quoted instead): %q", n, got)
+	}
+}
+
+func countRunes(s string, r rune) int {
+	n := 0
+	for _, c := range s {
+		if c == r {
+			n++
+		}
+	}
+	return n
+}
+
+func TestJSONEncoder(t *testing.T) {
+	got := string(JSONEncoder{}.Encode("INFO", "hello", []Field{KV("body", "line1\nline2"), KV("n", 3)}))
+	want := `{"level":"INFO","msg":"hello","body":"line1\nline2","n":3}`
+	if got != want {
+		t.Errorf("Encode() = %q, want %q", got, want)
+	}
+}
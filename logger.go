@@ -0,0 +1,550 @@
+package vlog
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Logger carries its own V level, vmodule table, plain-family prefix and
+// flags, severity sinks and encoder, independent of every other Logger.
+// The package-level functions (V, Infof, SetLogLevel, SetVModule, and so
+// on) are thin shims over a default instance. Use New to give a subsystem
+// its own logging scope instead of sharing global state.
+//
+// A Logger must not be copied after first use.
+type Logger struct {
+	base *log.Logger // backs Print, Printf, Println, Panic* and Output
+	out  io.Writer   // passed to New; every severity's default sink
+
+	level uint
+
+	vmodulePats  atomic.Value // []modulePat
+	vmoduleCache atomic.Value // *sync.Map, callsite pc -> effective level
+
+	severityOutput  [numSeverity]io.Writer
+	stderrThreshold Severity
+	sevLoggers      [numSeverity]*log.Logger
+
+	encoder Encoder
+}
+
+// New creates a Logger that writes to out with the given prefix and flag,
+// mirroring log.New. Every severity defaults to writing to out too; use
+// SetSeverityOutput to give a severity an additional sink, or
+// SetStderrThreshold to also mirror severities at or above a level to the
+// real process stderr (useful once out has been redirected to a file).
+func New(out io.Writer, prefix string, flag int) *Logger {
+	l := &Logger{
+		base:            log.New(out, prefix, flag),
+		out:             out,
+		stderrThreshold: numSeverity, // disabled until SetStderrThreshold is called
+		encoder:         TextEncoder{},
+	}
+	l.vmodulePats.Store([]modulePat(nil))
+	l.vmoduleCache.Store(&sync.Map{})
+	for sev := InfoLog; sev < numSeverity; sev++ {
+		l.sevLoggers[sev] = log.New(io.Discard, string(severityChar[sev])+" ", flag)
+	}
+	l.rebuildSeverityLoggers()
+	return l
+}
+
+// std is the default Logger every package-level function shims over.
+var std = New(os.Stderr, "", LstdFlags)
+
+func init() {
+	flag.UintVar(&std.level, "v", 0, "log level for V logs")
+	flag.Var(vmoduleFlag{}, "vmodule", "comma-separated list of pattern=N settings for file-filtered logging")
+	flag.Var(stderrThresholdFlag{}, "stderrthreshold", "logs at or above this severity (INFO, WARNING, ERROR, FATAL) are also copied to stderr")
+}
+
+// modulePat is a single pattern=level pair parsed out of a -vmodule spec.
+type modulePat struct {
+	pattern string
+	literal bool // pattern is a literal string, not a glob pattern
+	level   uint
+}
+
+// match reports whether file, the caller's source file basename with its
+// .go suffix removed, satisfies the pattern.
+func (m modulePat) match(file string) bool {
+	if m.literal {
+		return file == m.pattern
+	}
+	match, _ := filepath.Match(m.pattern, file)
+	return match
+}
+
+// isLiteral reports whether pattern contains no glob metacharacters, so it
+// can be compared with a plain string equality instead of filepath.Match.
+func isLiteral(pattern string) bool {
+	return !strings.ContainsAny(pattern, `\*?[`)
+}
+
+// moduleName reduces a caller's source file path, as reported by
+// runtime.Caller, to the basename vmodule patterns are matched against.
+func moduleName(file string) string {
+	file = strings.TrimSuffix(file, ".go")
+	if i := strings.LastIndexByte(file, '/'); i >= 0 {
+		file = file[i+1:]
+	}
+	return file
+}
+
+// vmoduleFlag routes the -vmodule flag through std.SetVModule.
+type vmoduleFlag struct{}
+
+func (vmoduleFlag) String() string     { return "" }
+func (vmoduleFlag) Set(s string) error { return std.SetVModule(s) }
+
+// SetVModule sets l's -vmodule value, overriding any setting made on the
+// command line. The spec is a comma-separated list of pattern=level
+// pairs, e.g. "gopher*=2,rpc_client=3". Each pattern is matched with
+// shell-style globbing (see path/filepath.Match) against the basename of
+// the source file, minus its .go suffix, that calls V. When no pattern
+// matches a given callsite, V falls back to l's level.
+func (l *Logger) SetVModule(spec string) error {
+	var filter []modulePat
+	for _, piece := range strings.Split(spec, ",") {
+		if piece == "" {
+			continue
+		}
+		eq := strings.LastIndexByte(piece, '=')
+		if eq <= 0 || eq == len(piece)-1 {
+			return fmt.Errorf("vlog: malformed vmodule component: %q", piece)
+		}
+		pattern, levStr := piece[:eq], piece[eq+1:]
+		v, err := strconv.ParseUint(levStr, 10, 0)
+		if err != nil {
+			return fmt.Errorf("vlog: malformed vmodule level in %q: %v", piece, err)
+		}
+		filter = append(filter, modulePat{pattern, isLiteral(pattern), uint(v)})
+	}
+	l.vmodulePats.Store(filter)
+	l.vmoduleCache.Store(&sync.Map{}) // old cached levels may no longer be valid
+	return nil
+}
+
+// SetVModule is SetVModule on the default Logger.
+func SetVModule(spec string) error { return std.SetVModule(spec) }
+
+// callerLevel returns the V level that applies to l's caller's caller,
+// i.e. the function that called whichever of (*Logger).V or V invoked
+// callerLevel directly, consulting the vmodule table and its callsite
+// cache before falling back to l's level.
+func callerLevel(l *Logger) uint {
+	pats := l.vmodulePats.Load().([]modulePat)
+	if len(pats) == 0 {
+		return l.level
+	}
+	pc, file, _, ok := runtime.Caller(2)
+	if !ok {
+		return l.level
+	}
+	cache := l.vmoduleCache.Load().(*sync.Map)
+	if cached, ok := cache.Load(pc); ok {
+		return cached.(uint)
+	}
+	lvl := l.level
+	name := moduleName(file)
+	for _, m := range pats {
+		if m.match(name) {
+			lvl = m.level
+			break
+		}
+	}
+	cache.Store(pc, lvl)
+	return lvl
+}
+
+// V reports whether verbosity level v is enabled for l, for use as
+// `if l.V(2) { l.Infof(...) }`. Unlike the package-level V, the result
+// can't be chained into l.V(2).Infof(...): Verbose always logs through
+// the default Logger, so chaining it here would silently bypass l.
+func (l *Logger) V(v uint) bool {
+	return v <= callerLevel(l)
+}
+
+// Whether an individual call to V generates a log record depends on the
+// setting of level, unless the callsite's source file matches a pattern
+// registered with SetVModule or the -vmodule flag, in which case that
+// pattern's level takes precedence.
+func V(v uint) Verbose {
+	return Verbose(v <= callerLevel(std))
+}
+
+// SetLogLevel sets l's log level, just use at parameter initialize zone.
+func (l *Logger) SetLogLevel(v uint) {
+	l.level = v
+}
+
+// GetLogLevel returns l's log level, just use at parameter initialize zone.
+func (l *Logger) GetLogLevel() uint {
+	return l.level
+}
+
+// SetLogLevel sets the default Logger's log level.
+func SetLogLevel(v uint) { std.SetLogLevel(v) }
+
+// GetLogLevel returns the default Logger's log level.
+func GetLogLevel() uint { return std.GetLogLevel() }
+
+// rebuildSeverityLoggers recomputes each severity's output writer from
+// l.out, l.severityOutput and l.stderrThreshold.
+func (l *Logger) rebuildSeverityLoggers() {
+	for sev := InfoLog; sev < numSeverity; sev++ {
+		writers := []io.Writer{l.out}
+		for i := InfoLog; i <= sev; i++ {
+			if w := l.severityOutput[i]; w != nil {
+				writers = append(writers, w)
+			}
+		}
+		if sev >= l.stderrThreshold && l.out != io.Writer(os.Stderr) {
+			writers = append(writers, os.Stderr)
+		}
+		if len(writers) == 1 {
+			l.sevLoggers[sev].SetOutput(writers[0])
+		} else {
+			l.sevLoggers[sev].SetOutput(io.MultiWriter(writers...))
+		}
+	}
+}
+
+// SetSeverityOutput sets an additional output destination for messages
+// logged at sev. Per glog convention, messages at sev are also written to
+// every lower severity's sink; a nil w removes sev's own additional sink.
+// Like SetLogLevel, this is meant to be called during initialization, not
+// while logging concurrently.
+func (l *Logger) SetSeverityOutput(sev Severity, w io.Writer) {
+	l.severityOutput[sev] = w
+	l.rebuildSeverityLoggers()
+}
+
+// SetSeverityOutput is SetSeverityOutput on the default Logger.
+func SetSeverityOutput(sev Severity, w io.Writer) { std.SetSeverityOutput(sev, w) }
+
+// SetStderrThreshold sets the minimum severity l additionally copies to
+// the real process stderr (when l's own output isn't already stderr).
+// Like SetLogLevel, this is meant to be called during initialization.
+func (l *Logger) SetStderrThreshold(sev Severity) {
+	l.stderrThreshold = sev
+	l.rebuildSeverityLoggers()
+}
+
+// SetStderrThreshold is SetStderrThreshold on the default Logger.
+func SetStderrThreshold(sev Severity) { std.SetStderrThreshold(sev) }
+
+// stderrThresholdFlag routes the -stderrthreshold flag through
+// std.SetStderrThreshold.
+type stderrThresholdFlag struct{}
+
+func (stderrThresholdFlag) String() string {
+	if std.stderrThreshold >= numSeverity {
+		return ""
+	}
+	return severityName[std.stderrThreshold]
+}
+
+func (stderrThresholdFlag) Set(s string) error {
+	sev, err := parseSeverity(s)
+	if err != nil {
+		return err
+	}
+	std.SetStderrThreshold(sev)
+	return nil
+}
+
+// SetEncoder sets the Encoder l's Infow/Warnw/Errorw family uses to
+// render structured log records.
+func (l *Logger) SetEncoder(e Encoder) {
+	l.encoder = e
+}
+
+// SetEncoder is SetEncoder on the default Logger.
+func SetEncoder(e Encoder) { std.SetEncoder(e) }
+
+// logSeverity writes s, prefixed with sev's character, to every sink at
+// or below sev, flushing and exiting the process if sev is FatalLog.
+func logSeverity(l *Logger, sev Severity, s string) {
+	l.sevLoggers[sev].Output(3, s)
+	if sev == FatalLog {
+		l.flushSeverities()
+		os.Exit(1)
+	}
+}
+
+// flushSeverities flushes l's main output and every configured severity
+// sink that knows how to flush.
+func (l *Logger) flushSeverities() {
+	if f, ok := l.out.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	for _, w := range l.severityOutput {
+		if f, ok := w.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+	}
+}
+
+func logSeverityw(l *Logger, sev Severity, msg string, fields []Field) {
+	logSeverity(l, sev, string(l.encoder.Encode(severityName[sev], msg, fields)))
+}
+
+// Info logs to l's INFO severity sinks.
+// Arguments are handled in the manner of fmt.Print.
+func (l *Logger) Info(v ...interface{}) { logSeverity(l, InfoLog, fmt.Sprint(v...)) }
+
+// Infof logs to l's INFO severity sinks.
+// Arguments are handled in the manner of fmt.Printf.
+func (l *Logger) Infof(format string, v ...interface{}) {
+	logSeverity(l, InfoLog, fmt.Sprintf(format, v...))
+}
+
+// Infoln logs to l's INFO severity sinks.
+// Arguments are handled in the manner of fmt.Println.
+func (l *Logger) Infoln(v ...interface{}) { logSeverity(l, InfoLog, fmt.Sprintln(v...)) }
+
+// Info logs to the INFO severity sinks.
+// Arguments are handled in the manner of fmt.Print.
+func Info(v ...interface{}) { logSeverity(std, InfoLog, fmt.Sprint(v...)) }
+
+// Infof logs to the INFO severity sinks.
+// Arguments are handled in the manner of fmt.Printf.
+func Infof(format string, v ...interface{}) { logSeverity(std, InfoLog, fmt.Sprintf(format, v...)) }
+
+// Infoln logs to the INFO severity sinks.
+// Arguments are handled in the manner of fmt.Println.
+func Infoln(v ...interface{}) { logSeverity(std, InfoLog, fmt.Sprintln(v...)) }
+
+// Warning logs to l's WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Print.
+func (l *Logger) Warning(v ...interface{}) { logSeverity(l, WarningLog, fmt.Sprint(v...)) }
+
+// Warningf logs to l's WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Printf.
+func (l *Logger) Warningf(format string, v ...interface{}) {
+	logSeverity(l, WarningLog, fmt.Sprintf(format, v...))
+}
+
+// Warningln logs to l's WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Println.
+func (l *Logger) Warningln(v ...interface{}) { logSeverity(l, WarningLog, fmt.Sprintln(v...)) }
+
+// Warning logs to the WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Print.
+func Warning(v ...interface{}) { logSeverity(std, WarningLog, fmt.Sprint(v...)) }
+
+// Warningf logs to the WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Printf.
+func Warningf(format string, v ...interface{}) {
+	logSeverity(std, WarningLog, fmt.Sprintf(format, v...))
+}
+
+// Warningln logs to the WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Println.
+func Warningln(v ...interface{}) { logSeverity(std, WarningLog, fmt.Sprintln(v...)) }
+
+// Error logs to l's ERROR, WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Print.
+func (l *Logger) Error(v ...interface{}) { logSeverity(l, ErrorLog, fmt.Sprint(v...)) }
+
+// Errorf logs to l's ERROR, WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Printf.
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	logSeverity(l, ErrorLog, fmt.Sprintf(format, v...))
+}
+
+// Errorln logs to l's ERROR, WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Println.
+func (l *Logger) Errorln(v ...interface{}) { logSeverity(l, ErrorLog, fmt.Sprintln(v...)) }
+
+// Error logs to the ERROR, WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Print.
+func Error(v ...interface{}) { logSeverity(std, ErrorLog, fmt.Sprint(v...)) }
+
+// Errorf logs to the ERROR, WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Printf.
+func Errorf(format string, v ...interface{}) { logSeverity(std, ErrorLog, fmt.Sprintf(format, v...)) }
+
+// Errorln logs to the ERROR, WARNING and INFO severity sinks.
+// Arguments are handled in the manner of fmt.Println.
+func Errorln(v ...interface{}) { logSeverity(std, ErrorLog, fmt.Sprintln(v...)) }
+
+// Fatal logs to l's FATAL, ERROR, WARNING and INFO severity sinks,
+// flushes them, and then calls os.Exit(1). Arguments are handled in the
+// manner of fmt.Print.
+func (l *Logger) Fatal(v ...interface{}) { logSeverity(l, FatalLog, fmt.Sprint(v...)) }
+
+// Fatalf logs to l's FATAL, ERROR, WARNING and INFO severity sinks,
+// flushes them, and then calls os.Exit(1). Arguments are handled in the
+// manner of fmt.Printf.
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	logSeverity(l, FatalLog, fmt.Sprintf(format, v...))
+}
+
+// Fatalln logs to l's FATAL, ERROR, WARNING and INFO severity sinks,
+// flushes them, and then calls os.Exit(1). Arguments are handled in the
+// manner of fmt.Println.
+func (l *Logger) Fatalln(v ...interface{}) { logSeverity(l, FatalLog, fmt.Sprintln(v...)) }
+
+// Fatal logs to the FATAL, ERROR, WARNING and INFO severity sinks, flushes
+// them, and then calls os.Exit(1). Arguments are handled in the manner of
+// fmt.Print.
+func Fatal(v ...interface{}) { logSeverity(std, FatalLog, fmt.Sprint(v...)) }
+
+// Fatalf logs to the FATAL, ERROR, WARNING and INFO severity sinks, flushes
+// them, and then calls os.Exit(1). Arguments are handled in the manner of
+// fmt.Printf.
+func Fatalf(format string, v ...interface{}) { logSeverity(std, FatalLog, fmt.Sprintf(format, v...)) }
+
+// Fatalln logs to the FATAL, ERROR, WARNING and INFO severity sinks,
+// flushes them, and then calls os.Exit(1). Arguments are handled in the
+// manner of fmt.Println.
+func Fatalln(v ...interface{}) { logSeverity(std, FatalLog, fmt.Sprintln(v...)) }
+
+// Infow logs a structured message to l's INFO severity sinks, encoded
+// with l's Encoder.
+func (l *Logger) Infow(msg string, fields ...Field) { logSeverityw(l, InfoLog, msg, fields) }
+
+// Warnw logs a structured message to l's WARNING and INFO severity sinks,
+// encoded with l's Encoder.
+func (l *Logger) Warnw(msg string, fields ...Field) { logSeverityw(l, WarningLog, msg, fields) }
+
+// Errorw logs a structured message to l's ERROR, WARNING and INFO
+// severity sinks, encoded with l's Encoder.
+func (l *Logger) Errorw(msg string, fields ...Field) { logSeverityw(l, ErrorLog, msg, fields) }
+
+// Infow logs a structured message to the INFO severity sinks, encoded
+// with the Encoder set by SetEncoder.
+func Infow(msg string, fields ...Field) { logSeverityw(std, InfoLog, msg, fields) }
+
+// Warnw logs a structured message to the WARNING and INFO severity sinks,
+// encoded with the Encoder set by SetEncoder.
+func Warnw(msg string, fields ...Field) { logSeverityw(std, WarningLog, msg, fields) }
+
+// Errorw logs a structured message to the ERROR, WARNING and INFO
+// severity sinks, encoded with the Encoder set by SetEncoder.
+func Errorw(msg string, fields ...Field) { logSeverityw(std, ErrorLog, msg, fields) }
+
+// SetOutput sets l's plain Print family output and becomes every
+// severity's default sink too (see SetSeverityOutput to override an
+// individual severity).
+func (l *Logger) SetOutput(w io.Writer) {
+	l.base.SetOutput(w)
+	l.out = w
+	l.rebuildSeverityLoggers()
+}
+
+// SetOutput sets the output destination for the default Logger.
+func SetOutput(w io.Writer) { std.SetOutput(w) }
+
+// Flags returns l's output flags.
+func (l *Logger) Flags() int { return l.base.Flags() }
+
+// Flags returns the output flags for the default Logger.
+func Flags() int { return std.Flags() }
+
+// SetFlags sets l's output flags, including every severity logger's
+// (Info, Warning, Error, Fatal and their w/f variants), so it takes effect
+// across l's whole API, not just the plain Print family.
+func (l *Logger) SetFlags(flag int) {
+	l.base.SetFlags(flag)
+	for sev := InfoLog; sev < numSeverity; sev++ {
+		l.sevLoggers[sev].SetFlags(flag)
+	}
+}
+
+// SetFlags sets the output flags for the default Logger.
+func SetFlags(flag int) { std.SetFlags(flag) }
+
+// Prefix returns l's output prefix.
+func (l *Logger) Prefix() string { return l.base.Prefix() }
+
+// Prefix returns the output prefix for the default Logger.
+func Prefix() string { return std.Prefix() }
+
+// SetPrefix sets l's output prefix. It only affects the plain Print family;
+// the severity loggers keep their own "I "/"W "/"E "/"F " prefix so log
+// lines stay filterable by severity.
+func (l *Logger) SetPrefix(prefix string) { l.base.SetPrefix(prefix) }
+
+// SetPrefix sets the output prefix for the default Logger.
+func SetPrefix(prefix string) { std.SetPrefix(prefix) }
+
+// Writer returns l's output destination, matching the addition Go 1.13
+// made to the standard library's log.Logger.
+func (l *Logger) Writer() io.Writer { return l.out }
+
+// These functions write to the standard logger.
+
+// Print calls Output to print to l.
+// Arguments are handled in the manner of fmt.Print.
+func (l *Logger) Print(v ...interface{}) { l.base.Print(v...) }
+
+// Printf calls Output to print to l.
+// Arguments are handled in the manner of fmt.Printf.
+func (l *Logger) Printf(format string, v ...interface{}) { l.base.Printf(format, v...) }
+
+// Println calls Output to print to l.
+// Arguments are handled in the manner of fmt.Println.
+func (l *Logger) Println(v ...interface{}) { l.base.Println(v...) }
+
+// Print calls Output to print to the standard logger.
+// Arguments are handled in the manner of fmt.Print.
+func Print(v ...interface{}) { std.Print(v...) }
+
+// Printf calls Output to print to the standard logger.
+// Arguments are handled in the manner of fmt.Printf.
+func Printf(format string, v ...interface{}) { std.Printf(format, v...) }
+
+// Println calls Output to print to the standard logger.
+// Arguments are handled in the manner of fmt.Println.
+func Println(v ...interface{}) { std.Println(v...) }
+
+// Panic is equivalent to l.Print() followed by a call to panic().
+func (l *Logger) Panic(v ...interface{}) { l.base.Panic(v...) }
+
+// Panicf is equivalent to l.Printf() followed by a call to panic().
+func (l *Logger) Panicf(format string, v ...interface{}) { l.base.Panicf(format, v...) }
+
+// Panicln is equivalent to l.Println() followed by a call to panic().
+func (l *Logger) Panicln(v ...interface{}) { l.base.Panicln(v...) }
+
+// Panic is equivalent to Print() followed by a call to panic().
+func Panic(v ...interface{}) { std.Panic(v...) }
+
+// Panicf is equivalent to Printf() followed by a call to panic().
+func Panicf(format string, v ...interface{}) { std.Panicf(format, v...) }
+
+// Panicln is equivalent to Println() followed by a call to panic().
+func Panicln(v ...interface{}) { std.Panicln(v...) }
+
+// Output writes the output for a logging event. The string s contains
+// the text to print after the prefix specified by the flags of l. A
+// newline is appended if the last character of s is not already a
+// newline. Calldepth is the count of the number of frames to skip when
+// computing the file name and line number if Llongfile or Lshortfile is
+// set; a value of 1 will print the details for the caller of Output.
+func (l *Logger) Output(calldepth int, s string) error {
+	return l.base.Output(calldepth+1, s)
+}
+
+// Output writes the output for a logging event. The string s contains
+// the text to print after the prefix specified by the flags of the
+// Logger. A newline is appended if the last character of s is not
+// already a newline. Calldepth is the count of the number of frames to
+// skip when computing the file name and line number if Llongfile or
+// Lshortfile is set; a value of 1 will print the details for the caller
+// of Output.
+func Output(calldepth int, s string) error {
+	return std.Output(calldepth+1, s)
+}
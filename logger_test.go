@@ -0,0 +1,45 @@
+package vlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestLoggersAreIndependent is the core promise of the Logger type: two
+// instances must not share level, vmodule, flags or output with each other
+// or with the package-level default.
+func TestLoggersAreIndependent(t *testing.T) {
+	var bufA, bufB bytes.Buffer
+	a := New(&bufA, "", 0)
+	b := New(&bufB, "", 0)
+
+	a.SetLogLevel(2)
+	b.SetLogLevel(0)
+	if !a.V(2) {
+		t.Error("a.V(2) = false, want true")
+	}
+	if b.V(2) {
+		t.Error("b.V(2) = true, want false: b's level was never raised")
+	}
+
+	a.Info("from-a")
+	b.Info("from-b")
+	if !strings.Contains(bufA.String(), "from-a") || strings.Contains(bufA.String(), "from-b") {
+		t.Errorf("a's output = %q, want only from-a", bufA.String())
+	}
+	if !strings.Contains(bufB.String(), "from-b") || strings.Contains(bufB.String(), "from-a") {
+		t.Errorf("b's output = %q, want only from-b", bufB.String())
+	}
+}
+
+func TestSetFlagsPropagatesToSeverityLoggers(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, "", 0)
+	l.SetFlags(Lshortfile)
+	l.Info("hello")
+
+	if !strings.Contains(buf.String(), "logger_test.go:") {
+		t.Errorf("Info output = %q, want it to carry the Lshortfile flag set via SetFlags", buf.String())
+	}
+}
@@ -0,0 +1,229 @@
+// Package rotate provides a rolling file-backed io.Writer suitable for
+// plugging into vlog.SetOutput or vlog.SetSeverityOutput.
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingWriter is an io.Writer that rolls its underlying file over once
+// it grows past MaxSize or has been open longer than MaxAge. Old files are
+// pruned in the background once MaxBackups or MaxAge is exceeded.
+//
+// Filename is a template for the log file path; it may reference {host},
+// {pid}, {sev} and {ts}, substituted with the local hostname, the process
+// id, Sev, and the rotation timestamp respectively, resembling glog's log
+// file naming. Sev is typically the name of the severity a RotatingWriter
+// is plugged into via vlog.SetSeverityOutput, and is otherwise left blank.
+//
+// A RotatingWriter must not be copied after first use.
+type RotatingWriter struct {
+	Filename   string
+	Sev        string
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+	Compress   bool
+	LocalTime  bool
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	opened  time.Time
+	pattern string // Filename with {host}/{pid}/{sev} resolved; {ts} still literal
+}
+
+// Write implements io.Writer, rotating to a new file first if p would push
+// the current file past MaxSize or MaxAge has elapsed since it was opened.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case w.file == nil:
+		if err := w.openNew(); err != nil {
+			return 0, err
+		}
+	case w.MaxSize > 0 && w.size+int64(len(p)) > w.MaxSize,
+		w.MaxAge > 0 && w.now().Sub(w.opened) > w.MaxAge:
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush commits any buffered data to the underlying file, so callers (and
+// vlog's Fatal path) can guarantee durability before exit.
+func (w *RotatingWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close flushes and closes the current file. A subsequent Write reopens a
+// new one.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate swaps in a new file, handing the old one off to a background
+// goroutine for compression and pruning.
+func (w *RotatingWriter) rotate() error {
+	old := w.file
+	if err := w.openNew(); err != nil {
+		return err
+	}
+	go w.finalize(old)
+	return nil
+}
+
+// openNew opens the next rotated file. {ts} only has second resolution, so
+// two rotations within the same second would otherwise collide on the same
+// path; it opens with O_EXCL and, on a collision, appends an incrementing
+// counter to {ts} until it finds an unused name, guaranteeing every rotation
+// gets its own file.
+func (w *RotatingWriter) openNew() error {
+	if w.pattern == "" {
+		w.pattern = resolveStatic(w.Filename, w.Sev)
+	}
+	ts := w.now().Format("20060102-150405")
+	name := strings.ReplaceAll(w.pattern, "{ts}", ts)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	for i := 1; os.IsExist(err); i++ {
+		name = strings.ReplaceAll(w.pattern, "{ts}", fmt.Sprintf("%s.%d", ts, i))
+		f, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	w.file = f
+	w.size = 0
+	w.opened = w.now()
+	return nil
+}
+
+// finalize closes the file the writer just rotated away from, optionally
+// gzip-compressing it, then prunes the backups directory.
+func (w *RotatingWriter) finalize(f *os.File) {
+	name := f.Name()
+	f.Close()
+	if w.Compress {
+		if err := compressFile(name); err == nil {
+			os.Remove(name)
+		}
+	}
+	w.prune()
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// prune removes backups beyond MaxBackups and older than MaxAge.
+func (w *RotatingWriter) prune() {
+	if w.MaxBackups <= 0 && w.MaxAge <= 0 {
+		return
+	}
+
+	dir := filepath.Dir(w.pattern)
+	globPat := strings.ReplaceAll(filepath.Base(w.pattern), "{ts}", "*")
+	matches, _ := filepath.Glob(filepath.Join(dir, globPat))
+	gzMatches, _ := filepath.Glob(filepath.Join(dir, globPat+".gz"))
+	matches = append(matches, gzMatches...)
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	backups := make([]backup, 0, len(matches))
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err == nil {
+			backups = append(backups, backup{m, fi.ModTime()})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+
+	var remove []string
+	if w.MaxBackups > 0 && len(backups) > w.MaxBackups {
+		for _, b := range backups[w.MaxBackups:] {
+			remove = append(remove, b.path)
+		}
+		backups = backups[:w.MaxBackups]
+	}
+	if w.MaxAge > 0 {
+		cutoff := w.now().Add(-w.MaxAge)
+		for _, b := range backups {
+			if b.modTime.Before(cutoff) {
+				remove = append(remove, b.path)
+			}
+		}
+	}
+	for _, p := range remove {
+		os.Remove(p)
+	}
+}
+
+func (w *RotatingWriter) now() time.Time {
+	if w.LocalTime {
+		return time.Now()
+	}
+	return time.Now().UTC()
+}
+
+func resolveStatic(tmpl, sev string) string {
+	host, _ := os.Hostname()
+	r := strings.NewReplacer(
+		"{host}", host,
+		"{pid}", strconv.Itoa(os.Getpid()),
+		"{sev}", sev,
+	)
+	return r.Replace(tmpl)
+}
@@ -0,0 +1,199 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// sortedByModTime returns matches ordered oldest-first, since files rotated
+// within the same second don't sort that way lexicographically.
+func sortedByModTime(t *testing.T, matches []string) []string {
+	t.Helper()
+	sort.Slice(matches, func(i, j int) bool {
+		fi, err := os.Stat(matches[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		fj, err := os.Stat(matches[j])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.ModTime().Before(fj.ModTime())
+	})
+	return matches
+}
+
+func TestWritePastMaxSizeProducesDistinctFiles(t *testing.T) {
+	dir := t.TempDir()
+	w := &RotatingWriter{
+		Filename: filepath.Join(dir, "app-{ts}.log"),
+		MaxSize:  10,
+	}
+	defer w.Close()
+
+	lines := []string{"111111\n", "222222\n", "333333\n", "444444\n", "555555\n"}
+	for _, line := range lines {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write(%q): %v", line, err)
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != len(lines) {
+		t.Fatalf("got %d files, want %d (one per write, since each forces a rotation): %v", len(matches), len(lines), matches)
+	}
+
+	var got string
+	for _, m := range sortedByModTime(t, matches) {
+		b, err := ioutil.ReadFile(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got += string(b)
+	}
+	var want string
+	for _, line := range lines {
+		want += line
+	}
+	if got != want {
+		t.Errorf("combined file content = %q, want %q", got, want)
+	}
+}
+
+func TestCompressRemovesOldFileAfterGzip(t *testing.T) {
+	dir := t.TempDir()
+	w := &RotatingWriter{
+		Filename: filepath.Join(dir, "app-{ts}.log"),
+		MaxSize:  10,
+		Compress: true,
+	}
+	defer w.Close()
+
+	lines := []string{"111111\n", "222222\n"}
+	for _, line := range lines {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write(%q): %v", line, err)
+		}
+	}
+
+	// finalize runs in a background goroutine; wait for it to compress and
+	// remove the file Write(lines[1]) rotated away from.
+	var gz, logs []string
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		gz, _ = filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+		logs, _ = filepath.Glob(filepath.Join(dir, "app-*.log"))
+		if len(gz) == 1 && len(logs) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(gz) != 1 {
+		t.Fatalf("got %d .gz files, want 1", len(gz))
+	}
+	if len(logs) != 1 {
+		t.Fatalf("got %d uncompressed .log files, want 1 (the still-open current file): %v", len(logs), logs)
+	}
+
+	f, err := os.Open(gz[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	defer gr.Close()
+	b, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != lines[0] {
+		t.Errorf("decompressed content = %q, want %q", string(b), lines[0])
+	}
+}
+
+func TestPruneRemovesBeyondMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	w := &RotatingWriter{
+		Filename:   filepath.Join(dir, "app-{ts}.log"),
+		MaxBackups: 2,
+	}
+	w.pattern = w.Filename
+
+	names := []string{"app-20260101-000000.log", "app-20260102-000000.log", "app-20260103-000000.log"}
+	for i, name := range names {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Date(2026, 1, i+1, 0, 0, 0, 0, time.UTC)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.prune()
+
+	remaining, err := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("got %d files after prune, want 2 (MaxBackups): %v", len(remaining), remaining)
+	}
+	for _, want := range names[1:] {
+		found := false
+		for _, r := range remaining {
+			if filepath.Base(r) == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected newer backup %s to survive pruning, remaining=%v", want, remaining)
+		}
+	}
+}
+
+func TestPruneRemovesOlderThanMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	w := &RotatingWriter{
+		Filename: filepath.Join(dir, "app-{ts}.log"),
+		MaxAge:   time.Hour,
+	}
+	w.pattern = w.Filename
+
+	oldPath := filepath.Join(dir, "app-old.log")
+	newPath := filepath.Join(dir, "app-new.log")
+	for _, p := range []string{oldPath, newPath} {
+		if err := ioutil.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := w.now().Add(-2 * time.Hour)
+	recent := w.now().Add(-time.Minute)
+	if err := os.Chtimes(oldPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(newPath, recent, recent); err != nil {
+		t.Fatal(err)
+	}
+
+	w.prune()
+
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Errorf("backup past MaxAge should have been removed, stat err = %v", err)
+	}
+	if _, err := os.Stat(newPath); err != nil {
+		t.Errorf("backup within MaxAge should survive pruning: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package vlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSeverityOutputCascadesToHigherSeveritiesOnly(t *testing.T) {
+	var main, errBuf bytes.Buffer
+	l := New(&main, "", 0)
+	l.SetSeverityOutput(ErrorLog, &errBuf)
+
+	l.Info("info-line")
+	l.Warning("warning-line")
+	l.Error("error-line")
+
+	for _, want := range []string{"info-line", "warning-line", "error-line"} {
+		if !strings.Contains(main.String(), want) {
+			t.Errorf("main sink missing %q: %s", want, main.String())
+		}
+	}
+	if strings.Contains(errBuf.String(), "info-line") || strings.Contains(errBuf.String(), "warning-line") {
+		t.Errorf("error sink should only mirror Error and above, got: %s", errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "error-line") {
+		t.Errorf("error sink missing error-line: %s", errBuf.String())
+	}
+}
+
+func TestStderrThresholdMirrorsAtOrAboveThreshold(t *testing.T) {
+	origStderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+
+	var main bytes.Buffer
+	l := New(&main, "", 0)
+	l.SetStderrThreshold(WarningLog)
+	l.Info("info-line")
+	l.Warning("warning-line")
+
+	os.Stderr = origStderr
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(out), "info-line") {
+		t.Errorf("stderr got info-line, want only Warning and above: %s", out)
+	}
+	if !strings.Contains(string(out), "warning-line") {
+		t.Errorf("stderr missing warning-line: %s", out)
+	}
+}
+
+func TestStderrThresholdDisabledByDefault(t *testing.T) {
+	origStderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+
+	l := New(&bytes.Buffer{}, "", 0)
+	l.Error("should-not-reach-stderr")
+
+	os.Stderr = origStderr
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Errorf("stderr = %q, want empty: stderrthreshold is disabled until SetStderrThreshold is called", out)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    Severity
+		wantErr bool
+	}{
+		{"INFO", InfoLog, false},
+		{"warning", WarningLog, false},
+		{"Error", ErrorLog, false},
+		{"FATAL", FatalLog, false},
+		{"bogus", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := parseSeverity(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseSeverity(%q) = nil error, want one", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSeverity(%q) unexpected error: %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Errorf("parseSeverity(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
@@ -19,6 +19,24 @@
 //  vlog.SetLogLevel(3)
 //  vlog.GetLogLevel()
 //
+// The level of an individual source file can be raised independently of
+// the global level with the -vmodule flag or SetVModule, e.g.
+// -vmodule=gopher*=2,rpc_client=3.
+//
+// Orthogonal to the V-level, messages can also be logged at a named
+// severity (Info, Warning, Error, Fatal), each routable to its own sink
+// with SetSeverityOutput; see the -stderrthreshold flag to control which
+// severities are additionally copied to stderr. The vlog/rotate subpackage
+// provides a size/time-rolling io.Writer for use as such a sink.
+//
+// Structured logging is available alongside the above: vlog.Infow (and
+// Warnw/Errorw) take a message and a list of vlog.KV fields, rendered by
+// the Encoder set with SetEncoder (TextEncoder by default, or JSONEncoder).
+//
+// All of the above is a thin shim over a default *Logger; New creates
+// independent Loggers, each with its own level, vmodule table, sinks and
+// encoder, for subsystems that want their own logging scope.
+//
 //	vlog.Println("Prepare to repel boarders")
 //
 //	vlog.Fatalf("Initialization failed: %s", err)
@@ -33,9 +51,12 @@
 package vlog
 
 import (
-	"flag"
-	"io"
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
+	"unicode"
 )
 
 // These flags define which text to prefix to each log entry generated by the Logger.
@@ -58,134 +79,216 @@ const (
 	LstdFlags     = log.Ldate | log.Ltime // initial values for the standard logger
 )
 
-var level uint
-
 // Verbose is a boolean type that implements Print like function.
 // See the documentation of V for more information.
 type Verbose bool
 
-func init() {
-	flag.UintVar(&level, "v", 0, "log level for V logs")
+func (vb Verbose) Printf(format string, v ...interface{}) {
+	if vb {
+		Printf(format, v...)
+	}
 }
 
-// Set log level, just use at parameter initialize zone.
-func SetLogLevel(v uint) {
-	level = v
+func (vb Verbose) Println(v ...interface{}) {
+	if vb {
+		Println(v...)
+	}
 }
 
-// Get log level, just use at parameter initialize zone.
-func GetLogLevel() uint {
-	return level
+func (vb Verbose) Print(v ...interface{}) {
+	if vb {
+		Print(v...)
+	}
 }
 
-func (vb Verbose) Printf(format string, v ...interface{}) {
+func (vb Verbose) Infof(format string, v ...interface{}) {
 	if vb {
-		log.Printf(format, v)
+		Infof(format, v...)
 	}
 }
 
-func (vb Verbose) Println(v ...interface{}) {
+func (vb Verbose) Info(v ...interface{}) {
 	if vb {
-		log.Println(v)
+		Info(v...)
 	}
 }
 
-func (vb Verbose) Print(v ...interface{}) {
+func (vb Verbose) Infoln(v ...interface{}) {
 	if vb {
-		log.Print(v)
+		Infoln(v...)
 	}
 }
 
-// Whether an individual call to V generates a log record depends on the setting of level.
-func V(v uint) Verbose {
-	if v <= level {
-		return true
+func (vb Verbose) Warningf(format string, v ...interface{}) {
+	if vb {
+		Warningf(format, v...)
 	}
-	return false
 }
 
-// SetOutput sets the output destination for the standard logger.
-func SetOutput(w io.Writer) {
-	log.SetOutput(w)
+func (vb Verbose) Warning(v ...interface{}) {
+	if vb {
+		Warning(v...)
+	}
 }
 
-// Flags returns the output flags for the standard logger.
-func Flags() int {
-	return log.Flags()
+func (vb Verbose) Warningln(v ...interface{}) {
+	if vb {
+		Warningln(v...)
+	}
+}
+
+func (vb Verbose) Errorf(format string, v ...interface{}) {
+	if vb {
+		Errorf(format, v...)
+	}
 }
 
-// SetFlags sets the output flags for the standard logger.
-func SetFlags(flag int) {
-	log.Flags()
+func (vb Verbose) Error(v ...interface{}) {
+	if vb {
+		Error(v...)
+	}
 }
 
-// Prefix returns the output prefix for the standard logger.
-func Prefix() string {
-	return log.Prefix()
+func (vb Verbose) Errorln(v ...interface{}) {
+	if vb {
+		Errorln(v...)
+	}
 }
 
-// SetPrefix sets the output prefix for the standard logger.
-func SetPrefix(prefix string) {
-	log.SetPrefix(prefix)
+func (vb Verbose) Infow(msg string, fields ...Field) {
+	if vb {
+		Infow(msg, fields...)
+	}
 }
 
-// These functions write to the standard logger.
+func (vb Verbose) Warnw(msg string, fields ...Field) {
+	if vb {
+		Warnw(msg, fields...)
+	}
+}
 
-// Print calls Output to print to the standard logger.
-// Arguments are handled in the manner of fmt.Print.
-func Print(v ...interface{}) {
-	log.Print(v)
+func (vb Verbose) Errorw(msg string, fields ...Field) {
+	if vb {
+		Errorw(msg, fields...)
+	}
 }
 
-// Printf calls Output to print to the standard logger.
-// Arguments are handled in the manner of fmt.Printf.
-func Printf(format string, v ...interface{}) {
-	log.Printf(format, v)
+// Severity identifies the severity of a log message, orthogonal to the
+// numeric V-level.
+type Severity int32
+
+// Severity levels, in increasing order. A message logged at a given
+// severity is also written to every lower severity's sink, mirroring glog.
+const (
+	InfoLog Severity = iota
+	WarningLog
+	ErrorLog
+	FatalLog
+	numSeverity
+)
+
+// severityChar holds the one-character prefix written before each line,
+// e.g. "I" for InfoLog, so multi-sink output stays greppable.
+var severityChar = [numSeverity]byte{'I', 'W', 'E', 'F'}
+
+// severityName holds the names accepted by -stderrthreshold.
+var severityName = [numSeverity]string{"INFO", "WARNING", "ERROR", "FATAL"}
+
+func parseSeverity(s string) (Severity, error) {
+	for i, name := range severityName {
+		if strings.EqualFold(s, name) {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("vlog: invalid value %q for -stderrthreshold", s)
 }
 
-// Println calls Output to print to the standard logger.
-// Arguments are handled in the manner of fmt.Println.
-func Println(v ...interface{}) {
-	log.Println(v)
+// Field is a single structured logging key/value pair, created with KV.
+type Field struct {
+	Key   string
+	Value interface{}
 }
 
-// Fatal is equivalent to Print() followed by a call to os.Exit(1).
-func Fatal(v ...interface{}) {
-	log.Fatal(v)
+// KV creates a Field for use with Infow, Warnw and Errorw, e.g.
+// vlog.Infow("listening", vlog.KV("addr", addr)).
+func KV(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
 }
 
-// Fatalf is equivalent to Printf() followed by a call to os.Exit(1).
-func Fatalf(format string, v ...interface{}) {
-	log.Fatalf(format, v)
+// Encoder renders a structured log record into a line of output. level is
+// one of the severityName values ("INFO", "WARNING", "ERROR", "FATAL").
+type Encoder interface {
+	Encode(level, msg string, fields []Field) []byte
 }
 
-// Fatalln is equivalent to Println() followed by a call to os.Exit(1).
-func Fatalln(v ...interface{}) {
-	log.Fatalln()
+// TextEncoder renders a record as logfmt-style "k=v" pairs, so switching
+// to structured logging doesn't change the plain-text shape of existing
+// output.
+type TextEncoder struct{}
+
+// Encode implements Encoder.
+func (TextEncoder) Encode(level, msg string, fields []Field) []byte {
+	var b strings.Builder
+	b.WriteString("level=")
+	b.WriteString(level)
+	b.WriteString(" msg=")
+	writeTextValue(&b, msg)
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		writeTextValue(&b, f.Value)
+	}
+	return []byte(b.String())
 }
 
-// Panic is equivalent to Print() followed by a call to panic().
-func Panic(v ...interface{}) {
-	log.Panic(v)
+func writeTextValue(b *strings.Builder, v interface{}) {
+	s := fmt.Sprint(v)
+	if needsTextQuote(s) {
+		fmt.Fprintf(b, "%q", s)
+	} else {
+		b.WriteString(s)
+	}
 }
 
-// Panicf is equivalent to Printf() followed by a call to panic().
-func Panicf(format string, v ...interface{}) {
-	log.Panicf(format, v)
+// needsTextQuote reports whether s must be quoted to keep it on a single
+// logfmt line: besides the field-syntax characters, a literal newline or
+// any other control char would otherwise break the one-severity-prefix-
+// per-line guarantee that multi-sink output relies on.
+func needsTextQuote(s string) bool {
+	if strings.ContainsAny(s, " \t\"=") {
+		return true
+	}
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
 }
 
-// Panicln is equivalent to Println() followed by a call to panic().
-func Panicln(v ...interface{}) {
-	log.Panicln(v)
+// JSONEncoder renders a record as a single JSON object, with level and msg
+// followed by fields in the order they were given.
+type JSONEncoder struct{}
+
+// Encode implements Encoder.
+func (JSONEncoder) Encode(level, msg string, fields []Field) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	writeJSONField(&b, "level", level, true)
+	writeJSONField(&b, "msg", msg, false)
+	for _, f := range fields {
+		writeJSONField(&b, f.Key, f.Value, false)
+	}
+	b.WriteByte('}')
+	return b.Bytes()
 }
 
-// Output writes the output for a logging event.  The string s contains
-// the text to print after the prefix specified by the flags of the
-// Logger.  A newline is appended if the last character of s is not
-// already a newline.  Calldepth is the count of the number of
-// frames to skip when computing the file name and line number
-// if Llongfile or Lshortfile is set; a value of 1 will print the details
-// for the caller of Output.
-func Output(calldepth int, s string) error {
-	return log.Output(calldepth, s)
+func writeJSONField(b *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		b.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteByte(':')
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(v)
 }
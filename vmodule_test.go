@@ -0,0 +1,56 @@
+package vlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetVModuleOverridesLevelForMatchingFile(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	l.SetLogLevel(0)
+
+	// This test's own source file is vmodule_test.go, so a literal pattern
+	// on its basename (minus .go) matches every V call below.
+	if err := l.SetVModule("vmodule_test=2"); err != nil {
+		t.Fatalf("SetVModule: %v", err)
+	}
+	if !l.V(2) {
+		t.Error("V(2) = false, want true: vmodule override should beat the base level of 0")
+	}
+	if l.V(3) {
+		t.Error("V(3) = true, want false: level 3 exceeds the vmodule override of 2")
+	}
+}
+
+func TestSetVModuleGlobPattern(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	if err := l.SetVModule("vmodule_*=5"); err != nil {
+		t.Fatalf("SetVModule: %v", err)
+	}
+	if !l.V(5) {
+		t.Error("V(5) = false, want true: vmodule_test should match the vmodule_* glob")
+	}
+}
+
+func TestSetVModuleFallsBackToLevelWhenNoPatternMatches(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	l.SetLogLevel(1)
+	if err := l.SetVModule("nonexistent_file=9"); err != nil {
+		t.Fatalf("SetVModule: %v", err)
+	}
+	if !l.V(1) {
+		t.Error("V(1) = false, want true: no pattern matches, should fall back to l.level")
+	}
+	if l.V(2) {
+		t.Error("V(2) = true, want false: no pattern matches and 2 exceeds l.level")
+	}
+}
+
+func TestSetVModuleRejectsMalformedSpec(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	for _, spec := range []string{"noequals", "=2", "file="} {
+		if err := l.SetVModule(spec); err == nil {
+			t.Errorf("SetVModule(%q) = nil error, want one", spec)
+		}
+	}
+}